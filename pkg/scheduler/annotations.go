@@ -0,0 +1,95 @@
+/*
+Copyright 2024 The HAMi Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package scheduler
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/klog/v2"
+)
+
+// nvidiaAnnotationResources maps a pod annotation key to the nvidia
+// resource name it should be rewritten into. This is an nvidia-only
+// convenience today; there is no per-vendor hook to plug additional
+// vendors' aliases into yet.
+var nvidiaAnnotationResources = map[string]string{
+	"hami.sh/pod-gpu-memory": "nvidia.com/gpumem",
+	"hami.sh/pod-gpu-cores":  "nvidia.com/gpucores",
+	"hami.sh/gpu-count":      "nvidia.com/gpu",
+}
+
+// applyAnnotationResourceOverrides recognizes the hami.sh/pod-gpu-memory,
+// hami.sh/pod-gpu-cores and hami.sh/gpu-count annotations and rewrites them
+// into the equivalent nvidia resource requests/limits on the pod's first
+// GPU-requesting container, so users get a stable request surface without
+// hand-editing every workload's resource block. Resources explicitly set on
+// the container always win over an annotation.
+func applyAnnotationResourceOverrides(pod *corev1.Pod) {
+	if len(pod.Annotations) == 0 || len(pod.Spec.Containers) == 0 {
+		return
+	}
+
+	container := firstGPURequestingContainer(pod)
+	for annotation, resourceName := range nvidiaAnnotationResources {
+		value, ok := pod.Annotations[annotation]
+		if !ok || value == "" {
+			continue
+		}
+		quantity, err := resource.ParseQuantity(value)
+		if err != nil {
+			klog.Warningf("ignoring annotation %s=%q on pod %s/%s: %v", annotation, value, pod.Namespace, pod.Name, err)
+			continue
+		}
+		setContainerResource(container, corev1.ResourceName(resourceName), quantity)
+		klog.Infof("translated annotation %s=%s into resource %s=%s on container %s",
+			annotation, value, resourceName, quantity.String(), container.Name)
+	}
+}
+
+// firstGPURequestingContainer returns the first container that already
+// requests a GPU resource, so annotation-driven resources land on the
+// container they're meant for rather than an unrelated sidecar at index 0.
+// When no container requests one yet (the common case for pods that rely
+// entirely on annotations instead of an explicit resource block), it falls
+// back to the pod's first container.
+func firstGPURequestingContainer(pod *corev1.Pod) *corev1.Container {
+	for i := range pod.Spec.Containers {
+		if hasGPUResources(&pod.Spec.Containers[i]) {
+			return &pod.Spec.Containers[i]
+		}
+	}
+	return &pod.Spec.Containers[0]
+}
+
+// setContainerResource sets resourceName to quantity in both the
+// container's Requests and Limits, creating the maps as needed, unless the
+// container already declares that resource explicitly.
+func setContainerResource(container *corev1.Container, resourceName corev1.ResourceName, quantity resource.Quantity) {
+	_, inRequests := container.Resources.Requests[resourceName]
+	_, inLimits := container.Resources.Limits[resourceName]
+	if inRequests || inLimits {
+		return
+	}
+	if container.Resources.Requests == nil {
+		container.Resources.Requests = corev1.ResourceList{}
+	}
+	if container.Resources.Limits == nil {
+		container.Resources.Limits = corev1.ResourceList{}
+	}
+	container.Resources.Requests[resourceName] = quantity
+	container.Resources.Limits[resourceName] = quantity
+}
@@ -0,0 +1,111 @@
+/*
+Copyright 2024 The HAMi Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package scheduler
+
+import (
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestApplyAnnotationResourceOverridesTargetsGPURequestingContainer(t *testing.T) {
+	withTestVendorDomains(t, map[string]string{"nvidia.com": "nvidia"})
+
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Annotations: map[string]string{
+				"hami.sh/pod-gpu-memory": "8000",
+				"hami.sh/pod-gpu-cores":  "50",
+				"hami.sh/gpu-count":      "2",
+			},
+		},
+		Spec: corev1.PodSpec{
+			Containers: []corev1.Container{
+				{Name: "sidecar"},
+				{
+					Name: "main",
+					Resources: corev1.ResourceRequirements{
+						Requests: corev1.ResourceList{
+							corev1.ResourceName("nvidia.com/gpu"): resource.MustParse("1"),
+						},
+					},
+				},
+			},
+		},
+	}
+
+	applyAnnotationResourceOverrides(pod)
+
+	sidecar := pod.Spec.Containers[0]
+	if len(sidecar.Resources.Requests) != 0 {
+		t.Errorf("sidecar container should be untouched, got requests %v", sidecar.Resources.Requests)
+	}
+
+	main := pod.Spec.Containers[1]
+	if got := main.Resources.Requests[corev1.ResourceName("nvidia.com/gpumem")]; got.Value() != 8000 {
+		t.Errorf("main container gpumem request = %v, want 8000", got.Value())
+	}
+	if got := main.Resources.Requests[corev1.ResourceName("nvidia.com/gpucores")]; got.Value() != 50 {
+		t.Errorf("main container gpucores request = %v, want 50", got.Value())
+	}
+	// hami.sh/gpu-count should not override the explicit nvidia.com/gpu request already on the container.
+	if got := main.Resources.Requests[corev1.ResourceName("nvidia.com/gpu")]; got.Value() != 1 {
+		t.Errorf("main container gpu request = %v, want the pre-existing value of 1", got.Value())
+	}
+}
+
+func TestApplyAnnotationResourceOverridesFallsBackToFirstContainer(t *testing.T) {
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Annotations: map[string]string{
+				"hami.sh/gpu-count": "2",
+			},
+		},
+		Spec: corev1.PodSpec{
+			Containers: []corev1.Container{
+				{Name: "only"},
+			},
+		},
+	}
+
+	applyAnnotationResourceOverrides(pod)
+
+	if got := pod.Spec.Containers[0].Resources.Requests[corev1.ResourceName("nvidia.com/gpu")]; got.Value() != 2 {
+		t.Errorf("gpu request = %v, want 2", got.Value())
+	}
+}
+
+func TestApplyAnnotationResourceOverridesIgnoresInvalidValues(t *testing.T) {
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Annotations: map[string]string{
+				"hami.sh/gpu-count": "not-a-quantity",
+			},
+		},
+		Spec: corev1.PodSpec{
+			Containers: []corev1.Container{{Name: "only"}},
+		},
+	}
+
+	applyAnnotationResourceOverrides(pod)
+
+	if _, ok := pod.Spec.Containers[0].Resources.Requests[corev1.ResourceName("nvidia.com/gpu")]; ok {
+		t.Error("expected no gpu request to be set for an invalid annotation value")
+	}
+}
@@ -0,0 +1,211 @@
+/*
+Copyright 2024 The HAMi Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package scheduler
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/Project-HAMi/HAMi/pkg/scheduler/config"
+)
+
+// draClaimGVR identifies the Dynamic Resource Allocation ResourceClaim API
+// object. It is read via unstructured so this shim does not depend on the
+// typed DRA Go API; requests are made against resource.k8s.io/v1beta1.
+var draClaimGVR = schema.GroupVersionResource{Group: "resource.k8s.io", Version: "v1beta1", Resource: "resourceclaims"}
+
+// draShimmedAnnotation marks a pod whose DRA claims were converted into
+// plain HAMi resource requests, so operators can tell a "shimmed" pod from
+// one the real DRA driver handled end to end.
+const draShimmedAnnotation = "hami.io/dra-shimmed"
+
+// shimDRAResourceClaims detects pod.Spec.ResourceClaims that reference a
+// HAMi DeviceClass (config.HAMiDeviceClasses) and converts them into the
+// equivalent nvidia.com/gpumem, nvidia.com/gpucores and nvidia.com/gpu
+// requests on the container that actually references the claim (the same
+// resources device.GetDevices() already knows how to mutate and schedule),
+// then drops the claim so the core DRA scheduling plugin does not also try
+// to allocate it — on a cluster with no real driver for the class that
+// would otherwise leave the pod unschedulable despite the shimmed
+// resources, and on a cluster with a driver it would double-provision the
+// device. This lets HAMi participate in DRA-based workflows without a full
+// DRA driver.
+func (h *webhook) shimDRAResourceClaims(ctx context.Context, pod *corev1.Pod) error {
+	if h.draClient == nil || len(pod.Spec.ResourceClaims) == 0 {
+		return nil
+	}
+
+	retained := make([]corev1.PodResourceClaim, 0, len(pod.Spec.ResourceClaims))
+	shimmedNames := map[string]bool{}
+
+	for _, podClaim := range pod.Spec.ResourceClaims {
+		if podClaim.ResourceClaimName == nil {
+			retained = append(retained, podClaim)
+			continue
+		}
+
+		claim := &unstructured.Unstructured{}
+		claim.SetGroupVersionKind(draClaimGVR.GroupVersion().WithKind("ResourceClaim"))
+		if err := h.draClient.Get(ctx, client.ObjectKey{Namespace: pod.Namespace, Name: *podClaim.ResourceClaimName}, claim); err != nil {
+			return fmt.Errorf("fetching resource claim %s/%s: %w", pod.Namespace, *podClaim.ResourceClaimName, err)
+		}
+
+		// unstructured.Nested* only walk maps, so list fields are indexed here.
+		firstEntry := func(fields ...string) map[string]interface{} {
+			entries, _, _ := unstructured.NestedSlice(claim.Object, fields...)
+			if len(entries) == 0 {
+				return nil
+			}
+			first, _ := entries[0].(map[string]interface{})
+			return first
+		}
+		deviceClass, _, _ := unstructured.NestedString(firstEntry("spec", "devices", "requests"), "deviceClassName")
+		if !isHAMiDeviceClass(deviceClass) {
+			retained = append(retained, podClaim)
+			continue
+		}
+
+		container := containerRequestingClaim(pod, podClaim.Name)
+		if container == nil {
+			klog.Warningf("DRA claim %q on pod %s/%s has deviceClass %q but no container references it via resources.claims; leaving it unshimmed",
+				podClaim.Name, pod.Namespace, pod.Name, deviceClass)
+			retained = append(retained, podClaim)
+			continue
+		}
+
+		params, _, _ := unstructured.NestedMap(firstEntry("spec", "devices", "config"), "opaque", "parameters")
+		applyDRAClaimParameters(container, params)
+		shimmedNames[podClaim.Name] = true
+
+		klog.Infof("shimmed DRA claim %s/%s (deviceClass %s) into plain resource requests on container %s",
+			pod.Namespace, *podClaim.ResourceClaimName, deviceClass, container.Name)
+	}
+
+	pod.Spec.ResourceClaims = retained
+	if len(shimmedNames) == 0 {
+		return nil
+	}
+
+	for i := range pod.Spec.Containers {
+		c := &pod.Spec.Containers[i]
+		if len(c.Resources.Claims) == 0 {
+			continue
+		}
+		filtered := c.Resources.Claims[:0]
+		for _, claimRef := range c.Resources.Claims {
+			if shimmedNames[claimRef.Name] {
+				continue
+			}
+			filtered = append(filtered, claimRef)
+		}
+		c.Resources.Claims = filtered
+	}
+
+	if pod.Annotations == nil {
+		pod.Annotations = map[string]string{}
+	}
+	pod.Annotations[draShimmedAnnotation] = "true"
+	return nil
+}
+
+// containerRequestingClaim returns the container whose Resources.Claims
+// references claimAlias (the name used in pod.Spec.ResourceClaims), or nil
+// if no container does.
+func containerRequestingClaim(pod *corev1.Pod, claimAlias string) *corev1.Container {
+	for i := range pod.Spec.Containers {
+		c := &pod.Spec.Containers[i]
+		for _, claimRef := range c.Resources.Claims {
+			if claimRef.Name == claimAlias {
+				return c
+			}
+		}
+	}
+	return nil
+}
+
+// isHAMiDeviceClass reports whether deviceClass is one HAMi has been
+// configured to shim, via config.HAMiDeviceClasses.
+func isHAMiDeviceClass(deviceClass string) bool {
+	if deviceClass == "" {
+		return false
+	}
+	for _, name := range config.HAMiDeviceClasses {
+		if name == deviceClass {
+			return true
+		}
+	}
+	return false
+}
+
+// applyDRAClaimParameters maps the memory/cores/count/sharing.strategy
+// fields of a DRA claim's opaque parameters onto the equivalent HAMi
+// resource names. Parameters arrive as a map[string]interface{} decoded by
+// unstructured.NestedMap, so a numeric field (e.g. memory: 8000 in the
+// claim's JSON) comes through as int64, or float64 for a non-integral
+// value, rather than string; paramString normalizes all three so a
+// reasonably-typed claim schema is not silently skipped.
+func applyDRAClaimParameters(container *corev1.Container, params map[string]interface{}) {
+	paramString := func(key string) (string, bool) {
+		v, ok := params[key]
+		if !ok {
+			return "", false
+		}
+		switch t := v.(type) {
+		case string:
+			return t, true
+		case int64:
+			return strconv.FormatInt(t, 10), true
+		case float64:
+			return strconv.FormatFloat(t, 'f', -1, 64), true
+		default:
+			klog.Warningf("ignoring DRA claim parameter %q with unsupported type %T", key, v)
+			return "", false
+		}
+	}
+
+	if v, ok := paramString("memory"); ok {
+		setResourceFromString(container, "nvidia.com/gpumem", v)
+	}
+	if v, ok := paramString("cores"); ok {
+		setResourceFromString(container, "nvidia.com/gpucores", v)
+	}
+	if v, ok := paramString("count"); ok {
+		setResourceFromString(container, "nvidia.com/gpu", v)
+	}
+	// sharing.strategy has no direct resource equivalent; it is only logged,
+	// and no resource or annotation is set for it.
+	if v, ok := paramString("sharing.strategy"); ok {
+		klog.Infof("DRA claim requested sharing strategy %q for container %s; no resource equivalent, not applied", v, container.Name)
+	}
+}
+
+func setResourceFromString(container *corev1.Container, resourceName, value string) {
+	quantity, err := resource.ParseQuantity(value)
+	if err != nil {
+		klog.Warningf("ignoring invalid DRA claim parameter for %s: %v", resourceName, err)
+		return
+	}
+	setContainerResource(container, corev1.ResourceName(resourceName), quantity)
+}
@@ -0,0 +1,123 @@
+/*
+Copyright 2024 The HAMi Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package scheduler
+
+import (
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+
+	"github.com/Project-HAMi/HAMi/pkg/scheduler/config"
+)
+
+func TestApplyDRAClaimParameters(t *testing.T) {
+	container := &corev1.Container{}
+	params := map[string]interface{}{
+		"memory":           "8000",
+		"cores":            "50",
+		"count":            "2",
+		"sharing.strategy": "time-slicing",
+	}
+
+	applyDRAClaimParameters(container, params)
+
+	if got := container.Resources.Requests[corev1.ResourceName("nvidia.com/gpumem")]; got.Value() != 8000 {
+		t.Errorf("gpumem = %v, want 8000", got.Value())
+	}
+	if got := container.Resources.Requests[corev1.ResourceName("nvidia.com/gpucores")]; got.Value() != 50 {
+		t.Errorf("gpucores = %v, want 50", got.Value())
+	}
+	if got := container.Resources.Requests[corev1.ResourceName("nvidia.com/gpu")]; got.Value() != 2 {
+		t.Errorf("gpu = %v, want 2", got.Value())
+	}
+}
+
+// TestApplyDRAClaimParametersAcceptsNumericValues guards against claim
+// parameters decoded from JSON numbers (a claim schema that encodes
+// memory/cores/count as numbers rather than quoted strings) being silently
+// dropped; it covers the float64 form.
+func TestApplyDRAClaimParametersAcceptsNumericValues(t *testing.T) {
+	container := &corev1.Container{}
+	params := map[string]interface{}{
+		"memory": float64(8000),
+		"cores":  float64(50),
+		"count":  float64(2),
+	}
+
+	applyDRAClaimParameters(container, params)
+
+	if got := container.Resources.Requests[corev1.ResourceName("nvidia.com/gpumem")]; got.Value() != 8000 {
+		t.Errorf("gpumem = %v, want 8000", got.Value())
+	}
+	if got := container.Resources.Requests[corev1.ResourceName("nvidia.com/gpucores")]; got.Value() != 50 {
+		t.Errorf("gpucores = %v, want 50", got.Value())
+	}
+	if got := container.Resources.Requests[corev1.ResourceName("nvidia.com/gpu")]; got.Value() != 2 {
+		t.Errorf("gpu = %v, want 2", got.Value())
+	}
+}
+
+func TestApplyDRAClaimParametersIgnoresMissingFields(t *testing.T) {
+	container := &corev1.Container{}
+
+	applyDRAClaimParameters(container, map[string]interface{}{})
+
+	if len(container.Resources.Requests) != 0 {
+		t.Errorf("expected no resources to be set, got %v", container.Resources.Requests)
+	}
+}
+
+func TestIsHAMiDeviceClass(t *testing.T) {
+	original := config.HAMiDeviceClasses
+	defer func() { config.HAMiDeviceClasses = original }()
+	config.HAMiDeviceClasses = []string{"hami-gpu.example.com"}
+
+	if !isHAMiDeviceClass("hami-gpu.example.com") {
+		t.Error("expected registered device class to be recognized")
+	}
+	if isHAMiDeviceClass("other-vendor.example.com") {
+		t.Error("expected unregistered device class to be rejected")
+	}
+	if isHAMiDeviceClass("") {
+		t.Error("expected empty device class to be rejected")
+	}
+}
+
+func TestContainerRequestingClaim(t *testing.T) {
+	pod := &corev1.Pod{
+		Spec: corev1.PodSpec{
+			Containers: []corev1.Container{
+				{Name: "sidecar"},
+				{
+					Name: "main",
+					Resources: corev1.ResourceRequirements{
+						Claims: []corev1.ResourceClaim{{Name: "gpu-claim"}},
+					},
+				},
+			},
+		},
+	}
+
+	got := containerRequestingClaim(pod, "gpu-claim")
+	if got == nil || got.Name != "main" {
+		t.Errorf("containerRequestingClaim() = %v, want the \"main\" container", got)
+	}
+
+	if got := containerRequestingClaim(pod, "no-such-claim"); got != nil {
+		t.Errorf("containerRequestingClaim() = %v, want nil", got)
+	}
+}
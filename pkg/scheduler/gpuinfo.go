@@ -0,0 +1,283 @@
+/*
+Copyright 2024 The HAMi Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package scheduler
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+	"strings"
+	"sync"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/klog/v2"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+const (
+	// gpuInfoConfigMapNamespace/gpuInfoConfigMapName identify the
+	// cluster-scoped ConfigMap publishing GPU model aliases and required
+	// capabilities that device-plugins label nodes with.
+	gpuInfoConfigMapNamespace = "hami-system"
+	gpuInfoConfigMapName      = "gpu-info"
+
+	gpuModelHintAnnotation             = "hami.sh/gpu-model"
+	minComputeCapabilityHintAnnotation = "hami.sh/min-compute-capability"
+	gpuModelNodeLabel                  = "hami.io/gpu-model"
+	gpuComputeCapabilityNodeLabel      = "hami.io/gpu-compute-capability"
+	gpuVRAMNodeLabel                   = "hami.io/gpu-vram-mib"
+)
+
+// gpuModelInfo describes one canonical GPU model entry in the gpu-info
+// ConfigMap: the aliases users may type, and the capabilities the model
+// guarantees.
+type gpuModelInfo struct {
+	Aliases           []string `json:"aliases"`
+	ComputeCapability string   `json:"computeCapability"`
+	MinDriverVersion  string   `json:"minDriverVersion"`
+	VRAMClassMiB      int64    `json:"vramClassMiB"`
+}
+
+// defaultGPUModelInfo seeds the gpu-info ConfigMap on first run so the
+// webhook has something to normalize against before an operator has
+// customized it.
+var defaultGPUModelInfo = map[string]gpuModelInfo{
+	"NVIDIA-A100": {
+		Aliases:           []string{"A100", "a100"},
+		ComputeCapability: "8.0",
+		VRAMClassMiB:      40960,
+	},
+	"NVIDIA-GeForce-RTX-4090": {
+		Aliases:           []string{"RTX-4090", "rtx4090", "4090"},
+		ComputeCapability: "8.9",
+		VRAMClassMiB:      24576,
+	},
+}
+
+// GPUInfoStore holds the in-memory view of the gpu-info ConfigMap,
+// refreshed by GPUInfoReconciler, and is consulted by the webhook to
+// normalize user-provided GPU model hints into canonical model names.
+type GPUInfoStore struct {
+	mu     sync.RWMutex
+	models map[string]gpuModelInfo
+}
+
+// NewGPUInfoStore returns an empty store; it is populated once the
+// reconciler's first Reconcile call reads the ConfigMap.
+func NewGPUInfoStore() *GPUInfoStore {
+	return &GPUInfoStore{models: map[string]gpuModelInfo{}}
+}
+
+func (s *GPUInfoStore) set(models map[string]gpuModelInfo) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.models = models
+}
+
+// canonicalModel resolves a user-provided alias (e.g. "RTX-4090") to the
+// canonical model name (e.g. "NVIDIA-GeForce-RTX-4090") published by the
+// gpu-info ConfigMap, returning the input unchanged if no alias matches.
+func (s *GPUInfoStore) canonicalModel(hint string) string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for canonical, info := range s.models {
+		if strings.EqualFold(canonical, hint) {
+			return canonical
+		}
+		for _, alias := range info.Aliases {
+			if strings.EqualFold(alias, hint) {
+				return canonical
+			}
+		}
+	}
+	return hint
+}
+
+// lookup returns the gpuModelInfo for a canonical model name, as resolved
+// by canonicalModel.
+func (s *GPUInfoStore) lookup(canonicalModel string) (gpuModelInfo, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	info, ok := s.models[canonicalModel]
+	return info, ok
+}
+
+// GPUInfoReconciler keeps a GPUInfoStore in sync with the gpu-info
+// ConfigMap and creates the ConfigMap with defaults if it is missing, so
+// the webhook always has an alias table to normalize hints against.
+type GPUInfoReconciler struct {
+	client.Client
+	Store *GPUInfoStore
+}
+
+// SetupWithManager registers the reconciler to watch ConfigMaps (Reconcile
+// ignores all but gpu-info) and runs one reconcile of gpu-info when the
+// manager starts, so a ConfigMap that is absent at startup is created.
+func (r *GPUInfoReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	initial := reconcile.Request{NamespacedName: types.NamespacedName{Namespace: gpuInfoConfigMapNamespace, Name: gpuInfoConfigMapName}}
+	if err := mgr.Add(manager.RunnableFunc(func(ctx context.Context) error {
+		if _, err := r.Reconcile(ctx, initial); err != nil {
+			klog.Errorf("initializing gpu-info ConfigMap %s/%s: %v", gpuInfoConfigMapNamespace, gpuInfoConfigMapName, err)
+		}
+		return nil
+	})); err != nil {
+		return err
+	}
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&corev1.ConfigMap{}).
+		Complete(r)
+}
+
+// Reconcile reloads the store from the gpu-info ConfigMap, initializing it
+// with defaultGPUModelInfo when the ConfigMap does not exist yet.
+func (r *GPUInfoReconciler) Reconcile(ctx context.Context, req reconcile.Request) (reconcile.Result, error) {
+	if req.Namespace != gpuInfoConfigMapNamespace || req.Name != gpuInfoConfigMapName {
+		return reconcile.Result{}, nil
+	}
+
+	cm := &corev1.ConfigMap{}
+	err := r.Get(ctx, types.NamespacedName{Namespace: gpuInfoConfigMapNamespace, Name: gpuInfoConfigMapName}, cm)
+	if apierrors.IsNotFound(err) {
+		klog.Infof("gpu-info ConfigMap %s/%s not found, creating with defaults", gpuInfoConfigMapNamespace, gpuInfoConfigMapName)
+		cm = newDefaultGPUInfoConfigMap()
+		if err := r.Create(ctx, cm); err != nil && !apierrors.IsAlreadyExists(err) {
+			return reconcile.Result{}, err
+		}
+		r.Store.set(defaultGPUModelInfo)
+		return reconcile.Result{}, nil
+	}
+	if err != nil {
+		return reconcile.Result{}, err
+	}
+
+	r.Store.set(parseGPUInfoConfigMap(cm))
+	return reconcile.Result{}, nil
+}
+
+func newDefaultGPUInfoConfigMap() *corev1.ConfigMap {
+	data := map[string]string{}
+	for model, info := range defaultGPUModelInfo {
+		encoded, err := json.Marshal(info)
+		if err != nil {
+			klog.Errorf("marshaling default gpu-info entry for %s: %v", model, err)
+			continue
+		}
+		data[model] = string(encoded)
+	}
+	return &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: gpuInfoConfigMapNamespace,
+			Name:      gpuInfoConfigMapName,
+		},
+		Data: data,
+	}
+}
+
+// parseGPUInfoConfigMap reads the gpu-info ConfigMap's data section, where
+// each key is a canonical model name and the value is the JSON encoding of
+// a gpuModelInfo, so every field (aliases, compute capability, driver
+// version, VRAM class) round-trips for models an operator adds beyond
+// defaultGPUModelInfo.
+func parseGPUInfoConfigMap(cm *corev1.ConfigMap) map[string]gpuModelInfo {
+	models := make(map[string]gpuModelInfo, len(cm.Data))
+	for model, encoded := range cm.Data {
+		var info gpuModelInfo
+		if err := json.Unmarshal([]byte(encoded), &info); err != nil {
+			klog.Warningf("ignoring malformed gpu-info entry for %s: %v", model, err)
+			continue
+		}
+		models[model] = info
+	}
+	return models
+}
+
+// injectGPUModelAffinity reads the hami.sh/gpu-model and
+// hami.sh/min-compute-capability annotations, normalizes the model hint
+// through store's alias table, and injects a nodeAffinity requirement
+// matching the labels HAMi device-plugins publish on nodes. When the model
+// hint resolves to a known entry, its required compute capability and VRAM
+// class are injected too, so "gpu-model: A100" alone implies the
+// capability constraints the model guarantees without the caller having to
+// spell out min-compute-capability by hand.
+func injectGPUModelAffinity(pod *corev1.Pod, store *GPUInfoStore) {
+	if store == nil {
+		return
+	}
+
+	var expressions []corev1.NodeSelectorRequirement
+	minComputeCapability := pod.Annotations[minComputeCapabilityHintAnnotation]
+
+	if hint := pod.Annotations[gpuModelHintAnnotation]; hint != "" {
+		canonical := store.canonicalModel(hint)
+		expressions = append(expressions, corev1.NodeSelectorRequirement{
+			Key:      gpuModelNodeLabel,
+			Operator: corev1.NodeSelectorOpIn,
+			Values:   []string{canonical},
+		})
+
+		if info, ok := store.lookup(canonical); ok {
+			if minComputeCapability == "" {
+				minComputeCapability = info.ComputeCapability
+			}
+			if info.VRAMClassMiB > 0 {
+				if expr, ok := atLeastOrdinalExpression(gpuVRAMNodeLabel, strconv.FormatInt(info.VRAMClassMiB, 10)); ok {
+					expressions = append(expressions, expr)
+				}
+			}
+		}
+	}
+
+	if minComputeCapability != "" {
+		if expr, ok := atLeastOrdinalExpression(gpuComputeCapabilityNodeLabel, computeCapabilityOrdinal(minComputeCapability)); ok {
+			expressions = append(expressions, expr)
+		}
+	}
+
+	addHAMiNodeAffinityExpressions(pod, expressions)
+}
+
+// computeCapabilityOrdinal turns a "major.minor" compute capability string
+// into the integer-encoded ordinal the hami.io/gpu-compute-capability node
+// label uses (e.g. "8.0" -> "80").
+func computeCapabilityOrdinal(cc string) string {
+	return strings.ReplaceAll(cc, ".", "")
+}
+
+// atLeastOrdinalExpression builds a "key >= ordinal" node-selector
+// requirement. corev1.NodeSelectorOpGt is a strict greater-than with no Gte
+// counterpart, so the floor is encoded as a strict Gt against ordinal-1:
+// a node labeled exactly the minimum (e.g. an A100 at compute capability
+// "80") must still match. Returns ok=false if ordinal does not parse,
+// rather than inject a requirement that silently excludes the floor value.
+func atLeastOrdinalExpression(key, ordinal string) (corev1.NodeSelectorRequirement, bool) {
+	value, err := strconv.ParseInt(ordinal, 10, 64)
+	if err != nil {
+		klog.Warningf("ignoring node affinity requirement for %s: %q is not a valid ordinal: %v", key, ordinal, err)
+		return corev1.NodeSelectorRequirement{}, false
+	}
+	return corev1.NodeSelectorRequirement{
+		Key:      key,
+		Operator: corev1.NodeSelectorOpGt,
+		Values:   []string{strconv.FormatInt(value-1, 10)},
+	}, true
+}
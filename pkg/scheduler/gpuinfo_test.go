@@ -0,0 +1,131 @@
+/*
+Copyright 2024 The HAMi Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package scheduler
+
+import (
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestCanonicalModel(t *testing.T) {
+	store := NewGPUInfoStore()
+	store.set(map[string]gpuModelInfo{
+		"NVIDIA-GeForce-RTX-4090": {Aliases: []string{"RTX-4090", "rtx4090", "4090"}},
+	})
+
+	tests := []struct {
+		hint string
+		want string
+	}{
+		{"RTX-4090", "NVIDIA-GeForce-RTX-4090"},
+		{"4090", "NVIDIA-GeForce-RTX-4090"},
+		{"nvidia-geforce-rtx-4090", "NVIDIA-GeForce-RTX-4090"}, // canonical name matched case-insensitively
+		{"unknown-model", "unknown-model"},                     // unmatched hints pass through unchanged
+	}
+
+	for _, tt := range tests {
+		if got := store.canonicalModel(tt.hint); got != tt.want {
+			t.Errorf("canonicalModel(%q) = %q, want %q", tt.hint, got, tt.want)
+		}
+	}
+}
+
+func TestComputeCapabilityOrdinal(t *testing.T) {
+	tests := []struct {
+		cc   string
+		want string
+	}{
+		{"8.0", "80"},
+		{"8.9", "89"},
+	}
+	for _, tt := range tests {
+		if got := computeCapabilityOrdinal(tt.cc); got != tt.want {
+			t.Errorf("computeCapabilityOrdinal(%q) = %q, want %q", tt.cc, got, tt.want)
+		}
+	}
+}
+
+// TestAtLeastOrdinalExpressionIncludesFloor guards against the off-by-one
+// where a node labeled exactly at the requested minimum would be excluded
+// by a strict Gt comparison.
+func TestAtLeastOrdinalExpressionIncludesFloor(t *testing.T) {
+	expr, ok := atLeastOrdinalExpression(gpuComputeCapabilityNodeLabel, computeCapabilityOrdinal("8.0"))
+	if !ok {
+		t.Fatal("atLeastOrdinalExpression() returned ok=false for a valid ordinal")
+	}
+	if expr.Operator != corev1.NodeSelectorOpGt {
+		t.Fatalf("Operator = %v, want Gt", expr.Operator)
+	}
+	if len(expr.Values) != 1 || expr.Values[0] != "79" {
+		t.Fatalf("Values = %v, want [\"79\"] so that a node labeled \"80\" still matches Gt", expr.Values)
+	}
+}
+
+func TestAtLeastOrdinalExpressionRejectsInvalidOrdinal(t *testing.T) {
+	if _, ok := atLeastOrdinalExpression(gpuComputeCapabilityNodeLabel, "not-a-number"); ok {
+		t.Error("expected atLeastOrdinalExpression() to reject a non-numeric ordinal")
+	}
+}
+
+func TestInjectGPUModelAffinityDerivesCapabilityFromModel(t *testing.T) {
+	store := NewGPUInfoStore()
+	store.set(map[string]gpuModelInfo{
+		"NVIDIA-A100": {
+			Aliases:           []string{"A100"},
+			ComputeCapability: "8.0",
+			VRAMClassMiB:      40960,
+		},
+	})
+
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Annotations: map[string]string{
+				gpuModelHintAnnotation: "A100",
+			},
+		},
+	}
+
+	injectGPUModelAffinity(pod, store)
+
+	if pod.Spec.Affinity == nil || pod.Spec.Affinity.NodeAffinity == nil {
+		t.Fatal("expected nodeAffinity to be injected")
+	}
+	terms := pod.Spec.Affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution.NodeSelectorTerms
+	if len(terms) != 1 {
+		t.Fatalf("expected a single node selector term, got %d", len(terms))
+	}
+
+	var sawModel, sawComputeCapability, sawVRAM bool
+	for _, expr := range terms[0].MatchExpressions {
+		switch expr.Key {
+		case gpuModelNodeLabel:
+			sawModel = true
+			if expr.Values[0] != "NVIDIA-A100" {
+				t.Errorf("model value = %v, want NVIDIA-A100", expr.Values)
+			}
+		case gpuComputeCapabilityNodeLabel:
+			sawComputeCapability = true
+		case gpuVRAMNodeLabel:
+			sawVRAM = true
+		}
+	}
+	if !sawModel || !sawComputeCapability || !sawVRAM {
+		t.Errorf("expected model, compute-capability and VRAM expressions, got %+v", terms[0].MatchExpressions)
+	}
+}
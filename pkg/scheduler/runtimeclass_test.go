@@ -0,0 +1,52 @@
+/*
+Copyright 2024 The HAMi Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package scheduler
+
+import (
+	"testing"
+
+	"github.com/Project-HAMi/HAMi/pkg/scheduler/config"
+)
+
+func TestRuntimeClassForVendor(t *testing.T) {
+	originalNames := config.RuntimeClassNames
+	originalGlobal := config.RuntimeClassName
+	defer func() {
+		config.RuntimeClassNames = originalNames
+		config.RuntimeClassName = originalGlobal
+	}()
+
+	config.RuntimeClassNames = map[string]string{
+		"amd": "kata-amd",
+	}
+	config.RuntimeClassName = "nvidia"
+
+	tests := []struct {
+		vendor string
+		want   string
+	}{
+		{"amd", "kata-amd"},
+		{"intel", "nvidia"}, // no explicit mapping, falls back to the global default
+		{"", "nvidia"},
+	}
+
+	for _, tt := range tests {
+		if got := runtimeClassForVendor(tt.vendor); got != tt.want {
+			t.Errorf("runtimeClassForVendor(%q) = %q, want %q", tt.vendor, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,49 @@
+/*
+Copyright 2024 The HAMi Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package scheduler
+
+import (
+	"testing"
+
+	"github.com/Project-HAMi/HAMi/pkg/scheduler/config"
+)
+
+func TestIsAllowedTopologyKey(t *testing.T) {
+	originalAllowed := config.AllowedTopologyKeys
+	defer func() { config.AllowedTopologyKeys = originalAllowed }()
+	config.AllowedTopologyKeys = []string{"topology.hami.io/rack"}
+
+	tests := []struct {
+		key  string
+		want bool
+	}{
+		{"kubernetes.io/hostname", true},
+		{"hami.io/gpu-uuid", true},
+		{"topology.hami.io/numa", true},
+		{"topology.hami.io/rack", true}, // via config.AllowedTopologyKeys
+		{"hami.io/not-allowed", false},
+		{"nvidia.com/gpu-uuid", false},
+		{"nvidia.com/gpu-something-else", false},
+		{"topology.kubernetes.io/zone", true}, // outside HAMi's namespace, left to the default scheduler
+	}
+
+	for _, tt := range tests {
+		if got := isAllowedTopologyKey(tt.key); got != tt.want {
+			t.Errorf("isAllowedTopologyKey(%q) = %v, want %v", tt.key, got, tt.want)
+		}
+	}
+}
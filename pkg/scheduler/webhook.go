@@ -19,12 +19,18 @@ package scheduler
 import (
 	"context"
 	"encoding/json"
+	"fmt"
 	"net/http"
+	"sort"
+	"strings"
 
 	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
 	"k8s.io/klog/v2"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 	logf "sigs.k8s.io/controller-runtime/pkg/log"
 	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
 
@@ -34,42 +40,265 @@ import (
 
 const template = "Processing admission hook for pod %v/%v, UID: %v"
 
+// gpuVendorLabel is the node label published by HAMi device plugins to
+// identify which vendor's GPUs a node exposes.
+const gpuVendorLabel = "hami.io/gpu-vendor"
+
 type webhook struct {
 	decoder *admission.Decoder
+	// eventRecorder is optional; when set it is used to surface mismatches
+	// between requested and injected pod configuration. It is wired in via
+	// SetEventRecorder once the manager's recorder is available, since
+	// NewWebHook runs before the manager is started.
+	eventRecorder record.EventRecorder
+	// draClient is optional; when set it is used to read DRA ResourceClaim
+	// objects so they can be shimmed into plain HAMi resource requests.
+	draClient client.Client
+	// gpuInfoStore is optional; when set it is used to normalize GPU model
+	// hints into node-affinity requirements.
+	gpuInfoStore *GPUInfoStore
+}
+
+// SetGPUInfoStore attaches the GPU model/capability store the webhook
+// reads when normalizing hami.sh/gpu-model hints into node affinity.
+func (h *webhook) SetGPUInfoStore(store *GPUInfoStore) {
+	h.gpuInfoStore = store
+}
+
+// SetDRAClient attaches a client the webhook can use to resolve DRA
+// ResourceClaim objects referenced by pod.Spec.ResourceClaims.
+func (h *webhook) SetDRAClient(c client.Client) {
+	h.draClient = c
+}
+
+// SetEventRecorder attaches an EventRecorder the webhook can use to emit
+// events on pods it mutates.
+func (h *webhook) SetEventRecorder(recorder record.EventRecorder) {
+	h.eventRecorder = recorder
+}
+
+// GPURequest describes the GPU capabilities a single container is asking
+// for, built from the registered device vendors rather than a hard-coded
+// resource-name list. It is the unit anti-affinity/node-affinity decisions
+// are based on.
+type GPURequest struct {
+	// Vendor is the device vendor that owns this request, e.g. "nvidia",
+	// "amd" or "intel".
+	Vendor string
+	// Count is the number of GPUs requested from this vendor.
+	Count int64
+	// MemoryMiB is the requested device memory, in MiB, if any.
+	MemoryMiB int64
+	// ComputeFraction is the requested compute share (0-100), if any.
+	ComputeFraction int64
+	// Capabilities lists the device capabilities implied by the requested
+	// resources, e.g. "compute", "utility".
+	Capabilities []string
+	// NonShareable is true when the requested resource type does not
+	// support sharing a single physical GPU across pods.
+	NonShareable bool
 }
 
 // hasGPUResources checks if a container requests any GPU resources
 func hasGPUResources(container *corev1.Container) bool {
+	return len(buildGPURequests(container)) > 0
+}
+
+// buildGPURequests inspects a container's resource requests/limits and
+// returns one GPURequest per vendor the container asks for, via
+// classifyGPUResource.
+func buildGPURequests(container *corev1.Container) []GPURequest {
 	if container.Resources.Requests == nil && container.Resources.Limits == nil {
-		return false
+		return nil
 	}
 
-	// Check both requests and limits for GPU resources
+	byVendor := map[string]*GPURequest{}
 	for _, resources := range []corev1.ResourceList{container.Resources.Requests, container.Resources.Limits} {
-		if resources == nil {
-			continue
+		for resourceName, quantity := range resources {
+			if quantity.IsZero() {
+				continue
+			}
+			vendor, capability, nonShareable, ok := classifyGPUResource(string(resourceName))
+			if !ok {
+				continue
+			}
+			req, exists := byVendor[vendor]
+			if !exists {
+				req = &GPURequest{Vendor: vendor}
+				byVendor[vendor] = req
+			}
+			req.NonShareable = req.NonShareable || nonShareable
+			switch capability {
+			case "count":
+				if v := quantity.Value(); v > req.Count {
+					req.Count = v
+				}
+			case "memory":
+				if v := quantity.Value(); v > req.MemoryMiB {
+					req.MemoryMiB = v
+				}
+			case "compute":
+				if v := quantity.Value(); v > req.ComputeFraction {
+					req.ComputeFraction = v
+				}
+			}
+			req.Capabilities = appendCapability(req.Capabilities, capability)
 		}
+	}
 
-		// Check for various GPU resource types
-		gpuResourceNames := []string{
-			"nvidia.com/gpu",
-			"nvidia.com/gpucores",
-			"nvidia.com/gpumem-percentage",
-			"nvidia.com/gpumem",
-			"amd.com/gpu",
-			"intel.com/gpu",
+	requests := make([]GPURequest, 0, len(byVendor))
+	for _, req := range byVendor {
+		requests = append(requests, *req)
+	}
+	// byVendor is a map, so its iteration order is randomized; sort by
+	// vendor name so a container requesting resources from more than one
+	// vendor gets the same ordering (and hence the same winningGPUVendor
+	// pick) across otherwise-identical admission requests.
+	sort.Slice(requests, func(i, j int) bool { return requests[i].Vendor < requests[j].Vendor })
+	return requests
+}
+
+// knownVendorDomains returns the resource-name domain (e.g. "nvidia.com")
+// for every vendor currently registered via device.GetDevices(), so a new
+// device plugin's resources are classified without editing this file.
+// Replaced in tests, since it otherwise depends on the real device registry
+// having been initialized.
+var knownVendorDomains = defaultKnownVendorDomains
+
+func defaultKnownVendorDomains() map[string]string {
+	devices := device.GetDevices()
+	domains := make(map[string]string, len(devices))
+	for name := range devices {
+		domains[strings.ToLower(name)+".com"] = strings.ToLower(name)
+	}
+	return domains
+}
+
+// classifyGPUResource maps a Kubernetes resource name to the vendor and
+// capability it represents. nonShareable is true for resources that hand
+// out a whole physical GPU, which is what anti-affinity decisions key off.
+func classifyGPUResource(resourceName string) (vendor, capability string, nonShareable, ok bool) {
+	domain, suffix, hasSlash := strings.Cut(resourceName, "/")
+	if !hasSlash {
+		return "", "", false, false
+	}
+
+	vendor, ok = knownVendorDomains()[domain]
+	if !ok {
+		return "", "", false, false
+	}
+
+	switch suffix {
+	case "gpu":
+		return vendor, "count", true, true
+	case "gpumem", "gpumem-percentage":
+		return vendor, "memory", false, true
+	case "gpucores":
+		return vendor, "compute", false, true
+	default:
+		return "", "", false, false
+	}
+}
+
+func appendCapability(capabilities []string, capability string) []string {
+	for _, c := range capabilities {
+		if c == capability {
+			return capabilities
 		}
+	}
+	return append(capabilities, capability)
+}
 
-		for _, resourceName := range gpuResourceNames {
-			if quantity, exists := resources[corev1.ResourceName(resourceName)]; exists {
-				if !quantity.IsZero() {
-					return true
-				}
-			}
+// injectTopologyConstraints translates the pod's aggregated GPU capability
+// requests into Kubernetes scheduling constraints: a pod anti-affinity term
+// on kubernetes.io/hostname when multiple non-shareable GPUs from the same
+// vendor are requested (keeping replicas of the pod on distinct nodes, see
+// ensureMultiGPUAntiAffinity), plus a nodeAffinity requirement on the
+// vendor label for each vendor in requests.
+func injectTopologyConstraints(pod *corev1.Pod, requests []GPURequest) {
+	for _, req := range requests {
+		if req.Vendor == "" {
+			continue
+		}
+		ensureVendorNodeAffinity(pod, req.Vendor)
+		if req.NonShareable && req.Count > 1 {
+			ensureMultiGPUAntiAffinity(pod)
 		}
 	}
+}
 
-	return false
+func ensureVendorNodeAffinity(pod *corev1.Pod, vendor string) {
+	addHAMiNodeAffinityExpressions(pod, []corev1.NodeSelectorRequirement{
+		{
+			Key:      gpuVendorLabel,
+			Operator: corev1.NodeSelectorOpIn,
+			Values:   []string{vendor},
+		},
+	})
+}
+
+// addHAMiNodeAffinityExpressions ANDs expressions into the single
+// NodeSelectorTerm HAMi owns in the pod's required node affinity, rather
+// than appending a new NodeSelectorTerm per call site. Separate
+// NodeSelectorTerms are OR'd together per the Kubernetes API — pushing one
+// term for vendor and another for GPU model would let a node satisfy
+// scheduling by matching either constraint alone instead of both, so every
+// HAMi-authored requirement for a pod must land in the same term.
+func addHAMiNodeAffinityExpressions(pod *corev1.Pod, expressions []corev1.NodeSelectorRequirement) {
+	if len(expressions) == 0 {
+		return
+	}
+
+	if pod.Spec.Affinity == nil {
+		pod.Spec.Affinity = &corev1.Affinity{}
+	}
+	if pod.Spec.Affinity.NodeAffinity == nil {
+		pod.Spec.Affinity.NodeAffinity = &corev1.NodeAffinity{}
+	}
+	na := pod.Spec.Affinity.NodeAffinity
+	if na.RequiredDuringSchedulingIgnoredDuringExecution == nil {
+		na.RequiredDuringSchedulingIgnoredDuringExecution = &corev1.NodeSelector{}
+	}
+	terms := na.RequiredDuringSchedulingIgnoredDuringExecution.NodeSelectorTerms
+	if len(terms) == 0 {
+		na.RequiredDuringSchedulingIgnoredDuringExecution.NodeSelectorTerms = []corev1.NodeSelectorTerm{
+			{MatchExpressions: expressions},
+		}
+		return
+	}
+	terms[0].MatchExpressions = append(terms[0].MatchExpressions, expressions...)
+}
+
+// ensureMultiGPUAntiAffinity adds a term keeping replicas of this workload
+// off the same node. It requires the pod to carry labels identifying it:
+// an empty MatchLabels selects every pod in the namespace (Namespaces
+// defaults to the pod's own namespace when unset), which would make the
+// GPU pod unschedulable on any node already hosting an unrelated pod from
+// the same namespace. Unlabeled pods are left without the term rather than
+// risk that.
+func ensureMultiGPUAntiAffinity(pod *corev1.Pod) {
+	if len(pod.Labels) == 0 {
+		klog.Warningf("skipping multi-GPU anti-affinity for pod %s/%s: pod has no labels to select replicas by",
+			pod.Namespace, pod.Name)
+		return
+	}
+
+	term := corev1.PodAffinityTerm{
+		LabelSelector: &metav1.LabelSelector{
+			MatchLabels: pod.Labels,
+		},
+		TopologyKey: corev1.LabelHostname,
+	}
+
+	if pod.Spec.Affinity == nil {
+		pod.Spec.Affinity = &corev1.Affinity{}
+	}
+	if pod.Spec.Affinity.PodAntiAffinity == nil {
+		pod.Spec.Affinity.PodAntiAffinity = &corev1.PodAntiAffinity{}
+	}
+	paa := pod.Spec.Affinity.PodAntiAffinity
+	paa.RequiredDuringSchedulingIgnoredDuringExecution = append(
+		paa.RequiredDuringSchedulingIgnoredDuringExecution, term)
 }
 
 // shouldInjectRuntimeClass determines if runtime class should be injected for this pod
@@ -103,13 +332,153 @@ func shouldInjectRuntimeClass(pod *corev1.Pod) bool {
 	return false
 }
 
-// injectRuntimeClass adds the NVIDIA runtime class to the pod spec
-func injectRuntimeClass(pod *corev1.Pod) {
-	if config.RuntimeClassName != "" {
-		pod.Spec.RuntimeClassName = &config.RuntimeClassName
-		klog.Infof("Injected runtime class '%s' for GPU pod %s/%s",
-			config.RuntimeClassName, pod.Namespace, pod.Name)
+// runtimeClassHintAnnotation lets users record which runtime class they
+// expected, so the webhook can flag when the vendor-derived choice differs.
+const runtimeClassHintAnnotation = "hami.io/runtime-class-hint"
+
+// winningGPUVendor returns the vendor requested by the pod's first
+// GPU-requesting container (the alphabetically first vendor name when that
+// container requests several), or "" if the pod requests no GPU resources.
+func winningGPUVendor(pod *corev1.Pod) string {
+	for _, container := range pod.Spec.Containers {
+		for _, req := range buildGPURequests(&container) {
+			if req.Vendor != "" {
+				return req.Vendor
+			}
+		}
+	}
+	return ""
+}
+
+// runtimeClassForVendor resolves the runtime class to inject for a given
+// GPU vendor, preferring the per-vendor config.RuntimeClassNames entry and
+// falling back to the global config.RuntimeClassName when the vendor has no
+// explicit mapping.
+func runtimeClassForVendor(vendor string) string {
+	if name, ok := config.RuntimeClassNames[vendor]; ok && name != "" {
+		return name
+	}
+	return config.RuntimeClassName
+}
+
+// injectRuntimeClass adds the vendor-appropriate runtime class to the pod
+// spec and, if an EventRecorder is available and the pod carries an
+// explicit runtime-class hint that disagrees with the injected class,
+// emits an event flagging the mismatch.
+func (h *webhook) injectRuntimeClass(pod *corev1.Pod, vendor string) {
+	runtimeClassName := runtimeClassForVendor(vendor)
+	if runtimeClassName == "" {
+		return
+	}
+	pod.Spec.RuntimeClassName = &runtimeClassName
+	klog.Infof("Injected runtime class '%s' for %s GPU pod %s/%s",
+		runtimeClassName, vendor, pod.Namespace, pod.Name)
+
+	if hint := pod.Annotations[runtimeClassHintAnnotation]; hint != "" && hint != runtimeClassName && h.eventRecorder != nil {
+		h.eventRecorder.Eventf(pod, corev1.EventTypeWarning, "RuntimeClassMismatch",
+			"injected runtime class %q for vendor %q differs from hinted runtime class %q", runtimeClassName, vendor, hint)
+	}
+}
+
+// defaultAllowedTopologyKeys lists the topology keys the HAMi scheduler can
+// actually honor in pod (anti-)affinity terms. config.AllowedTopologyKeys
+// can extend this list for deployments with custom topology labels.
+var defaultAllowedTopologyKeys = []string{
+	corev1.LabelHostname,
+	"hami.io/gpu-uuid",
+	"topology.hami.io/numa",
+}
+
+// hamiTopologyKeyPrefixes identifies the topology keys that only the HAMi
+// scheduler understands; any such key outside the allow-list cannot be
+// honored and must be rejected at admission time.
+var hamiTopologyKeyPrefixes = []string{
+	"hami.io/",
+	"topology.hami.io/",
+	"nvidia.com/gpu-",
+}
+
+// isAllowedTopologyKey reports whether topologyKey is either outside HAMi's
+// namespace (left to the default scheduler to interpret) or explicitly
+// allow-listed.
+func isAllowedTopologyKey(topologyKey string) bool {
+	isHAMiKey := false
+	for _, prefix := range hamiTopologyKeyPrefixes {
+		if strings.HasPrefix(topologyKey, prefix) {
+			isHAMiKey = true
+			break
+		}
+	}
+	if !isHAMiKey {
+		return true
+	}
+
+	for _, allowed := range defaultAllowedTopologyKeys {
+		if topologyKey == allowed {
+			return true
+		}
+	}
+	for _, allowed := range config.AllowedTopologyKeys {
+		if topologyKey == allowed {
+			return true
+		}
+	}
+	return false
+}
+
+// validateAffinityTopologyKeys walks the pod's PodAffinity/PodAntiAffinity
+// terms and returns an error describing the first term whose topology key
+// the HAMi scheduler cannot honor. It only applies to pods requesting
+// HAMi-managed GPU resources; pods without GPU resources are left for the
+// default scheduler to admit or deny on their own terms.
+func validateAffinityTopologyKeys(pod *corev1.Pod) error {
+	hasGPURequest := false
+	for _, container := range pod.Spec.Containers {
+		if hasGPUResources(&container) {
+			hasGPURequest = true
+			break
+		}
+	}
+	if !hasGPURequest || pod.Spec.Affinity == nil {
+		return nil
+	}
+
+	check := func(kind string, terms []corev1.PodAffinityTerm) error {
+		for _, term := range terms {
+			if !isAllowedTopologyKey(term.TopologyKey) {
+				return fmt.Errorf("%s term uses unsupported topology key %q; allowed keys are %v",
+					kind, term.TopologyKey, append(append([]string{}, defaultAllowedTopologyKeys...), config.AllowedTopologyKeys...))
+			}
+		}
+		return nil
+	}
+	checkWeighted := func(kind string, terms []corev1.WeightedPodAffinityTerm) error {
+		for _, w := range terms {
+			if !isAllowedTopologyKey(w.PodAffinityTerm.TopologyKey) {
+				return fmt.Errorf("%s term uses unsupported topology key %q; allowed keys are %v",
+					kind, w.PodAffinityTerm.TopologyKey, append(append([]string{}, defaultAllowedTopologyKeys...), config.AllowedTopologyKeys...))
+			}
+		}
+		return nil
+	}
+
+	if pa := pod.Spec.Affinity.PodAffinity; pa != nil {
+		if err := check("podAffinity", pa.RequiredDuringSchedulingIgnoredDuringExecution); err != nil {
+			return err
+		}
+		if err := checkWeighted("podAffinity", pa.PreferredDuringSchedulingIgnoredDuringExecution); err != nil {
+			return err
+		}
+	}
+	if paa := pod.Spec.Affinity.PodAntiAffinity; paa != nil {
+		if err := check("podAntiAffinity", paa.RequiredDuringSchedulingIgnoredDuringExecution); err != nil {
+			return err
+		}
+		if err := checkWeighted("podAntiAffinity", paa.PreferredDuringSchedulingIgnoredDuringExecution); err != nil {
+			return err
+		}
 	}
+	return nil
 }
 
 func NewWebHook() (*admission.Webhook, error) {
@@ -123,7 +492,7 @@ func NewWebHook() (*admission.Webhook, error) {
 	return wh, nil
 }
 
-func (h *webhook) Handle(_ context.Context, req admission.Request) admission.Response {
+func (h *webhook) Handle(ctx context.Context, req admission.Request) admission.Response {
 	pod := &corev1.Pod{}
 	err := h.decoder.Decode(req, pod)
 	if err != nil {
@@ -135,11 +504,25 @@ func (h *webhook) Handle(_ context.Context, req admission.Request) admission.Res
 		return admission.Denied("pod has no containers")
 	}
 	klog.Infof(template, req.Namespace, req.Name, req.UID)
+
+	if err := h.shimDRAResourceClaims(ctx, pod); err != nil {
+		klog.Errorf(template+" - Failed to shim DRA resource claims: %v", req.Namespace, req.Name, req.UID, err)
+		return admission.Errored(http.StatusInternalServerError, err)
+	}
+
+	applyAnnotationResourceOverrides(pod)
+	injectGPUModelAffinity(pod, h.gpuInfoStore)
+
+	if err := validateAffinityTopologyKeys(pod); err != nil {
+		klog.Warningf(template+" - Denying admission: %v", req.Namespace, req.Name, req.UID, err)
+		return admission.Denied(err.Error())
+	}
+
 	hasResource := false
 
 	// Check if we should inject runtime class for GPU workloads
 	if shouldInjectRuntimeClass(pod) {
-		injectRuntimeClass(pod)
+		h.injectRuntimeClass(pod, winningGPUVendor(pod))
 		klog.Infof(template+" - Injected runtime class for GPU pod", req.Namespace, req.Name, req.UID)
 	}
 
@@ -159,6 +542,10 @@ func (h *webhook) Handle(_ context.Context, req admission.Request) admission.Res
 			}
 			hasResource = hasResource || found
 		}
+
+		if requests := buildGPURequests(c); len(requests) > 0 {
+			injectTopologyConstraints(pod, requests)
+		}
 	}
 
 	if !hasResource {
@@ -0,0 +1,216 @@
+/*
+Copyright 2024 The HAMi Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package scheduler
+
+import (
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// withTestVendorDomains stubs knownVendorDomains for the duration of a test
+// so classifyGPUResource does not depend on the real device registry having
+// been initialized.
+func withTestVendorDomains(t *testing.T, domains map[string]string) {
+	t.Helper()
+	original := knownVendorDomains
+	knownVendorDomains = func() map[string]string { return domains }
+	t.Cleanup(func() { knownVendorDomains = original })
+}
+
+func TestClassifyGPUResource(t *testing.T) {
+	withTestVendorDomains(t, map[string]string{
+		"nvidia.com": "nvidia",
+		"amd.com":    "amd",
+		"intel.com":  "intel",
+	})
+
+	tests := []struct {
+		resourceName     string
+		wantVendor       string
+		wantCapability   string
+		wantNonShareable bool
+		wantOK           bool
+	}{
+		{"nvidia.com/gpu", "nvidia", "count", true, true},
+		{"nvidia.com/gpumem", "nvidia", "memory", false, true},
+		{"nvidia.com/gpumem-percentage", "nvidia", "memory", false, true},
+		{"nvidia.com/gpucores", "nvidia", "compute", false, true},
+		{"amd.com/gpu", "amd", "count", true, true},
+		{"intel.com/gpu", "intel", "count", true, true},
+		{"cpu", "", "", false, false},
+	}
+
+	for _, tt := range tests {
+		vendor, capability, nonShareable, ok := classifyGPUResource(tt.resourceName)
+		if vendor != tt.wantVendor || capability != tt.wantCapability || nonShareable != tt.wantNonShareable || ok != tt.wantOK {
+			t.Errorf("classifyGPUResource(%q) = (%q, %q, %v, %v), want (%q, %q, %v, %v)",
+				tt.resourceName, vendor, capability, nonShareable, ok,
+				tt.wantVendor, tt.wantCapability, tt.wantNonShareable, tt.wantOK)
+		}
+	}
+}
+
+func TestBuildGPURequests(t *testing.T) {
+	withTestVendorDomains(t, map[string]string{"nvidia.com": "nvidia"})
+
+	container := &corev1.Container{
+		Resources: corev1.ResourceRequirements{
+			Requests: corev1.ResourceList{
+				corev1.ResourceName("nvidia.com/gpu"):      resource.MustParse("2"),
+				corev1.ResourceName("nvidia.com/gpumem"):   resource.MustParse("8000"),
+				corev1.ResourceName("nvidia.com/gpucores"): resource.MustParse("50"),
+			},
+		},
+	}
+
+	requests := buildGPURequests(container)
+	if len(requests) != 1 {
+		t.Fatalf("buildGPURequests() returned %d requests, want 1", len(requests))
+	}
+
+	req := requests[0]
+	if req.Vendor != "nvidia" {
+		t.Errorf("Vendor = %q, want nvidia", req.Vendor)
+	}
+	if req.Count != 2 {
+		t.Errorf("Count = %d, want 2", req.Count)
+	}
+	if req.MemoryMiB != 8000 {
+		t.Errorf("MemoryMiB = %d, want 8000", req.MemoryMiB)
+	}
+	if req.ComputeFraction != 50 {
+		t.Errorf("ComputeFraction = %d, want 50", req.ComputeFraction)
+	}
+	if !req.NonShareable {
+		t.Errorf("NonShareable = false, want true (nvidia.com/gpu was requested)")
+	}
+}
+
+// TestBuildGPURequestsDeterministicVendorOrder guards against byVendor's
+// map iteration order leaking into the returned slice: winningGPUVendor
+// picks the first entry, so a container requesting more than one vendor's
+// resources must see the same order every time.
+func TestBuildGPURequestsDeterministicVendorOrder(t *testing.T) {
+	withTestVendorDomains(t, map[string]string{"nvidia.com": "nvidia", "amd.com": "amd", "intel.com": "intel"})
+
+	container := &corev1.Container{
+		Resources: corev1.ResourceRequirements{
+			Requests: corev1.ResourceList{
+				corev1.ResourceName("intel.com/gpu"):  resource.MustParse("1"),
+				corev1.ResourceName("nvidia.com/gpu"): resource.MustParse("1"),
+				corev1.ResourceName("amd.com/gpu"):    resource.MustParse("1"),
+			},
+		},
+	}
+
+	for i := 0; i < 10; i++ {
+		requests := buildGPURequests(container)
+		if len(requests) != 3 {
+			t.Fatalf("buildGPURequests() returned %d requests, want 3", len(requests))
+		}
+		if requests[0].Vendor != "amd" || requests[1].Vendor != "intel" || requests[2].Vendor != "nvidia" {
+			t.Fatalf("buildGPURequests() order = [%s, %s, %s], want [amd, intel, nvidia]",
+				requests[0].Vendor, requests[1].Vendor, requests[2].Vendor)
+		}
+	}
+}
+
+func TestBuildGPURequestsNoGPUResources(t *testing.T) {
+	container := &corev1.Container{
+		Resources: corev1.ResourceRequirements{
+			Requests: corev1.ResourceList{
+				corev1.ResourceCPU: resource.MustParse("1"),
+			},
+		},
+	}
+
+	if requests := buildGPURequests(container); len(requests) != 0 {
+		t.Errorf("buildGPURequests() = %v, want empty", requests)
+	}
+}
+
+// TestEnsureVendorNodeAffinityAndGPUModelAffinityShareTerm guards against
+// the two node-affinity injection sites each pushing their own
+// NodeSelectorTerm: since separate terms are OR'd, that would let a node
+// satisfy scheduling by matching the vendor alone, defeating model pinning.
+func TestEnsureVendorNodeAffinityAndGPUModelAffinityShareTerm(t *testing.T) {
+	store := NewGPUInfoStore()
+	store.set(map[string]gpuModelInfo{
+		"NVIDIA-A100": {Aliases: []string{"A100"}, ComputeCapability: "8.0"},
+	})
+
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Annotations: map[string]string{
+				gpuModelHintAnnotation: "A100",
+			},
+		},
+	}
+
+	ensureVendorNodeAffinity(pod, "nvidia")
+	injectGPUModelAffinity(pod, store)
+
+	if pod.Spec.Affinity == nil || pod.Spec.Affinity.NodeAffinity == nil {
+		t.Fatal("expected nodeAffinity to be injected")
+	}
+	terms := pod.Spec.Affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution.NodeSelectorTerms
+	if len(terms) != 1 {
+		t.Fatalf("expected vendor and model constraints to share a single AND'd term, got %d terms", len(terms))
+	}
+
+	var sawVendor, sawModel bool
+	for _, expr := range terms[0].MatchExpressions {
+		switch expr.Key {
+		case gpuVendorLabel:
+			sawVendor = true
+		case gpuModelNodeLabel:
+			sawModel = true
+		}
+	}
+	if !sawVendor || !sawModel {
+		t.Errorf("expected both vendor and model expressions in the shared term, got %+v", terms[0].MatchExpressions)
+	}
+}
+
+func TestEnsureMultiGPUAntiAffinitySkipsUnlabeledPods(t *testing.T) {
+	pod := &corev1.Pod{}
+
+	ensureMultiGPUAntiAffinity(pod)
+
+	if pod.Spec.Affinity != nil {
+		t.Errorf("expected no affinity to be injected for an unlabeled pod, got %+v", pod.Spec.Affinity)
+	}
+}
+
+func TestEnsureMultiGPUAntiAffinityWithLabels(t *testing.T) {
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{"app": "training"}},
+	}
+
+	ensureMultiGPUAntiAffinity(pod)
+
+	if pod.Spec.Affinity == nil || pod.Spec.Affinity.PodAntiAffinity == nil {
+		t.Fatal("expected a pod anti-affinity term to be injected for a labeled pod")
+	}
+	terms := pod.Spec.Affinity.PodAntiAffinity.RequiredDuringSchedulingIgnoredDuringExecution
+	if len(terms) != 1 || terms[0].TopologyKey != corev1.LabelHostname {
+		t.Errorf("unexpected anti-affinity terms: %+v", terms)
+	}
+}